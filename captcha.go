@@ -0,0 +1,297 @@
+package guestbook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	captchaProviderBuiltin   = "builtin"
+	captchaProviderHCaptcha  = "hcaptcha"
+	captchaProviderTurnstile = "turnstile"
+
+	captchaTTL        = 5 * time.Minute
+	captchaCodeLen    = 6
+	captchaCodeAlpha  = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	captchaImageWidth = 160
+	captchaImageHigh  = 60
+
+	captchaAudioSampleRate = 8000
+	captchaAudioToneMs     = 200
+	captchaAudioGapMs      = 100
+	captchaAudioBaseHz     = 440
+	captchaAudioStepHz     = 30
+)
+
+// CaptchaProvider verifies a submitted captcha response, decoupling
+// createEntry from any particular anti-spam mechanism.
+type CaptchaProvider interface {
+	Verify(id string, answer string) (bool, error)
+}
+
+// CaptchaIssuer is implemented by providers that can hand out their own
+// challenges, as opposed to ones (like hCaptcha) whose widget is rendered
+// entirely client-side.
+type CaptchaIssuer interface {
+	Issue() (id string, imagePNG []byte, err error)
+	// IssueAudio issues the same kind of challenge as an audio clip
+	// instead of an image, for visually impaired submitters.
+	IssueAudio() (id string, audioWAV []byte, err error)
+}
+
+// NewCaptchaProvider builds the CaptchaProvider selected via cfg.CaptchaProvider
+func NewCaptchaProvider(cfg Config, store Store) (CaptchaProvider, error) {
+	switch cfg.CaptchaProvider {
+	case "", captchaProviderBuiltin:
+		return NewBuiltinCaptchaProvider(store), nil
+	case captchaProviderHCaptcha:
+		return &httpVerifyCaptchaProvider{secret: cfg.CaptchaSecret, verifyURL: "https://hcaptcha.com/siteverify"}, nil
+	case captchaProviderTurnstile:
+		return &httpVerifyCaptchaProvider{secret: cfg.CaptchaSecret, verifyURL: "https://challenges.cloudflare.com/turnstile/v0/siteverify"}, nil
+	default:
+		return nil, fmt.Errorf("unknown captcha provider %q", cfg.CaptchaProvider)
+	}
+}
+
+// getCaptcha issues a new builtin captcha challenge. By default it returns
+// an image challenge; ?format=audio returns the accessible audio variant
+// of the same kind of challenge instead.
+func (s *Server) getCaptcha(w http.ResponseWriter, r *http.Request) {
+	issuer, ok := s.CaptchaProvider.(CaptchaIssuer)
+	if !ok {
+		http.Error(w, "captcha provider does not support server-side challenges", http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Query().Get("format") == "audio" {
+		id, audioWAV, err := issuer.IssueAudio()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id":               id,
+			"audio_wav_base64": base64.StdEncoding.EncodeToString(audioWAV),
+		})
+		return
+	}
+
+	id, imagePNG, err := issuer.Issue()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":               id,
+		"image_png_base64": base64.StdEncoding.EncodeToString(imagePNG),
+	})
+}
+
+// builtinCaptchaProvider implements a simple image CAPTCHA whose challenges
+// are kept behind the Store, with a TTL.
+type builtinCaptchaProvider struct {
+	store Store
+}
+
+// NewBuiltinCaptchaProvider returns a ready-to-use builtin CaptchaProvider.
+// store's underlying captchas table is expected to already exist (created
+// by Store.Init).
+func NewBuiltinCaptchaProvider(store Store) *builtinCaptchaProvider {
+	return &builtinCaptchaProvider{store: store}
+}
+
+func (p *builtinCaptchaProvider) Issue() (string, []byte, error) {
+	return p.issueChallenge(renderCaptchaImage)
+}
+
+func (p *builtinCaptchaProvider) IssueAudio() (string, []byte, error) {
+	return p.issueChallenge(renderCaptchaAudio)
+}
+
+// issueChallenge generates a fresh code, stores it with a TTL and renders
+// it with render, sharing the bookkeeping between the image and audio
+// variants of the challenge.
+func (p *builtinCaptchaProvider) issueChallenge(render func(code string) ([]byte, error)) (string, []byte, error) {
+	id, err := generateID()
+	if err != nil {
+		return "", nil, err
+	}
+	code, err := randomCaptchaCode()
+	if err != nil {
+		return "", nil, err
+	}
+
+	challenge := CaptchaChallenge{ID: id, Answer: code, ExpiresAt: time.Now().Add(captchaTTL)}
+	if err := p.store.CreateCaptchaChallenge(challenge); err != nil {
+		return "", nil, err
+	}
+
+	payload, err := render(code)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, payload, nil
+}
+
+func (p *builtinCaptchaProvider) Verify(id string, answer string) (bool, error) {
+	if id == "" || answer == "" {
+		return false, nil
+	}
+
+	stored, found, err := p.store.ConsumeCaptchaChallenge(id)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return false, nil
+	}
+	return strings.EqualFold(stored.Answer, answer), nil
+}
+
+// randomCaptchaCode generates a short, visually unambiguous code
+func randomCaptchaCode() (string, error) {
+	b := make([]byte, captchaCodeLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := make([]byte, captchaCodeLen)
+	for i, v := range b {
+		code[i] = captchaCodeAlpha[int(v)%len(captchaCodeAlpha)]
+	}
+	return string(code), nil
+}
+
+// renderCaptchaImage draws the code in plain text onto a flat light-grey
+// background as a PNG; no noise or distortion is added.
+func renderCaptchaImage(code string) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, captchaImageWidth, captchaImageHigh))
+	background := color.RGBA{R: 240, G: 240, B: 240, A: 255}
+	for x := 0; x < captchaImageWidth; x++ {
+		for y := 0; y < captchaImageHigh; y++ {
+			img.Set(x, y, background)
+		}
+	}
+
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.RGBA{R: 40, G: 40, B: 40, A: 255}),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(20, captchaImageHigh/2+5),
+	}
+	drawer.DrawString(code)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderCaptchaAudio renders code as a sequence of distinct tones, one per
+// character, as a mono 16-bit PCM WAV clip.
+func renderCaptchaAudio(code string) ([]byte, error) {
+	toneSamples := captchaAudioSampleRate * captchaAudioToneMs / 1000
+	gapSamples := captchaAudioSampleRate * captchaAudioGapMs / 1000
+
+	pcm := make([]byte, 0, len(code)*(toneSamples+gapSamples)*2)
+	for _, c := range code {
+		freq := captchaAudioToneHz(c)
+		for i := 0; i < toneSamples; i++ {
+			t := float64(i) / captchaAudioSampleRate
+			sample := int16(math.Sin(2*math.Pi*freq*t) * math.MaxInt16 * 0.6)
+			pcm = append(pcm, byte(sample), byte(sample>>8))
+		}
+		pcm = append(pcm, make([]byte, gapSamples*2)...)
+	}
+
+	return encodeWAV(pcm, captchaAudioSampleRate), nil
+}
+
+// captchaAudioToneHz maps a captcha alphabet character to an audible tone,
+// spreading the alphabet across a recognizable frequency range.
+func captchaAudioToneHz(c rune) float64 {
+	idx := strings.IndexRune(captchaCodeAlpha, c)
+	if idx < 0 {
+		idx = 0
+	}
+	return captchaAudioBaseHz + float64(idx)*captchaAudioStepHz
+}
+
+// encodeWAV wraps mono 16-bit little-endian PCM samples in a WAV container
+func encodeWAV(pcm []byte, sampleRate int) []byte {
+	const bitsPerSample = 16
+	const channels = 1
+	byteRate := sampleRate * channels * bitsPerSample / 8
+	blockAlign := channels * bitsPerSample / 8
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(byteRate))
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(bitsPerSample))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
+
+// httpVerifyCaptchaProvider implements the shared siteverify-style HTTP
+// protocol used by hCaptcha and Cloudflare Turnstile: the "id" isn't used
+// (those widgets have no server-issued challenge), and "answer" is the
+// token the client-side widget produced.
+type httpVerifyCaptchaProvider struct {
+	secret    string
+	verifyURL string
+}
+
+func (p *httpVerifyCaptchaProvider) Verify(id string, answer string) (bool, error) {
+	if answer == "" {
+		return false, nil
+	}
+
+	resp, err := http.PostForm(p.verifyURL, url.Values{
+		"secret":   {p.secret},
+		"response": {answer},
+	})
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.Success, nil
+}
@@ -0,0 +1,113 @@
+package guestbook
+
+import (
+	"testing"
+	"time"
+)
+
+// captchaFakeStore is a minimal in-memory Store double, just enough to
+// exercise builtinCaptchaProvider without a real SQLite database. Methods
+// outside the CAPTCHA challenge lifecycle are never expected to be called
+// here and panic if they are.
+type captchaFakeStore struct {
+	challenges map[string]CaptchaChallenge
+}
+
+func newCaptchaFakeStore() *captchaFakeStore {
+	return &captchaFakeStore{challenges: map[string]CaptchaChallenge{}}
+}
+
+func (f *captchaFakeStore) CreateCaptchaChallenge(challenge CaptchaChallenge) error {
+	f.challenges[challenge.ID] = challenge
+	return nil
+}
+
+func (f *captchaFakeStore) ConsumeCaptchaChallenge(id string) (CaptchaChallenge, bool, error) {
+	challenge, ok := f.challenges[id]
+	if !ok {
+		return CaptchaChallenge{}, false, nil
+	}
+	delete(f.challenges, id)
+	return challenge, true, nil
+}
+
+func (f *captchaFakeStore) Init() error                    { panic("not used by this test") }
+func (f *captchaFakeStore) InsertEntry(Entry) error        { panic("not used by this test") }
+func (f *captchaFakeStore) GetEntry(string) (Entry, error) { panic("not used by this test") }
+func (f *captchaFakeStore) ListApprovedEntries(EntryFilter) ([]Entry, int, error) {
+	panic("not used by this test")
+}
+func (f *captchaFakeStore) ListAllEntries() ([]Entry, error) { panic("not used by this test") }
+func (f *captchaFakeStore) ApproveEntry(string) (ApproveResult, error) {
+	panic("not used by this test")
+}
+func (f *captchaFakeStore) RejectEntry(string) (Entry, error) { panic("not used by this test") }
+func (f *captchaFakeStore) SetComment(string, string) (SetCommentResult, error) {
+	panic("not used by this test")
+}
+func (f *captchaFakeStore) MostRecentEntryFromIP(string) (Entry, error) {
+	panic("not used by this test")
+}
+func (f *captchaFakeStore) ListWebhooks() ([]Webhook, error) { panic("not used by this test") }
+func (f *captchaFakeStore) InsertWebhook(Webhook) error      { panic("not used by this test") }
+func (f *captchaFakeStore) DeleteWebhook(string) (bool, error) {
+	panic("not used by this test")
+}
+
+var _ Store = (*captchaFakeStore)(nil)
+
+func TestBuiltinCaptchaProviderVerify(t *testing.T) {
+	store := newCaptchaFakeStore()
+	provider := NewBuiltinCaptchaProvider(store)
+
+	if err := store.CreateCaptchaChallenge(CaptchaChallenge{
+		ID: "good", Answer: "ABC123", ExpiresAt: time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("CreateCaptchaChallenge: %v", err)
+	}
+	ok, err := provider.Verify("good", "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("expected a case-insensitive match to verify")
+	}
+
+	// The challenge is single-use: the same ID must fail the second time.
+	ok, err = provider.Verify("good", "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected a second verification of the same challenge to fail")
+	}
+
+	if err := store.CreateCaptchaChallenge(CaptchaChallenge{
+		ID: "expired", Answer: "XYZ999", ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("CreateCaptchaChallenge: %v", err)
+	}
+	ok, err = provider.Verify("expired", "XYZ999")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected an expired challenge to fail verification")
+	}
+
+	ok, err = provider.Verify("unknown", "whatever")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected an unknown challenge ID to fail verification")
+	}
+
+	ok, err = provider.Verify("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected an empty id/answer to fail verification")
+	}
+}
@@ -0,0 +1,96 @@
+// Command goguestbookd is the standalone GoGuestBook binary: it wires the
+// guestbook library up to environment-based configuration and a local
+// SQLite database.
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/hoffie/GoGuestBook"
+)
+
+func main() {
+	cfg, dbFile, listen := loadConfig()
+
+	db, err := sqlx.Connect("sqlite3", dbFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	store := guestbook.NewSQLiteStore(db)
+	if err := store.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	captchaProvider, err := guestbook.NewCaptchaProvider(cfg, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mailer := guestbook.NewSMTPMailer(cfg)
+
+	server := guestbook.NewServer(store, mailer, captchaProvider, cfg)
+
+	if cfg.InboundSMTPListen != "" {
+		go func() {
+			log.Fatal(server.RunInboundSMTPServer())
+		}()
+	}
+
+	log.Println(fmt.Sprintf("Server listening on %s", listen))
+	log.Fatal(http.ListenAndServe(listen, server.Routes()))
+}
+
+// loadConfig loads environment variables into a guestbook.Config
+func loadConfig() (cfg guestbook.Config, dbFile string, listen string) {
+	dbFile = getEnv("DB_FILE")
+	listen = getEnv("LISTEN")
+	cfg.SMTPHost = getEnv("SMTP_HOST")
+	cfg.SMTPPort = getEnv("SMTP_PORT")
+	cfg.SMTPUser = getEnv("SMTP_USER")
+	cfg.SMTPPass = getEnv("SMTP_PASS")
+	cfg.AdminEmail = getEnv("ADMIN_EMAIL")
+	cfg.AdminLang = getEnvOptional("ADMIN_LANG", "de")
+	cfg.AdminToken = getEnv("ADMIN_TOKEN")
+	cfg.CaptchaProvider = getEnvOptional("CAPTCHA_PROVIDER", "builtin")
+	cfg.CaptchaSecret = getEnvOptional("CAPTCHA_SECRET", "")
+	cfg.URL = getEnv("URL")
+	duration, err := time.ParseDuration(fmt.Sprintf("%ss", getEnv("ENTRY_WAIT_SECONDS")))
+	if err != nil {
+		log.Fatal("Failed to parse ENTRY_WAIT_SECONDS")
+	}
+	cfg.EntryWaitDuration = duration
+	cfg.InboundSMTPListen = getEnvOptional("INBOUND_SMTP_LISTEN", "")
+	cfg.InboundSMTPDomain = getEnvOptional("INBOUND_SMTP_DOMAIN", "")
+	if cfg.InboundSMTPListen != "" {
+		// Only required when the inbound SMTP server is actually enabled.
+		cfg.InboundSMTPSecret = getEnv("INBOUND_SMTP_SECRET")
+	}
+	return cfg, dbFile, listen
+}
+
+func getEnv(key string) string {
+	key = fmt.Sprintf("GGB_%s", key)
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	log.Fatal("Failed to get env variable ", key)
+	return ""
+}
+
+// getEnvOptional loads an environment variable, falling back to def if unset
+func getEnvOptional(key string, def string) string {
+	key = fmt.Sprintf("GGB_%s", key)
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,76 @@
+package guestbook
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/emersion/go-mbox"
+)
+
+const mboxSubjectPreviewLen = 60
+
+// exportEntriesMbox streams every entry, approved or not, as an mbox
+// archive for backup or migration into standard mail tooling.
+func (s *Server) exportEntriesMbox(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.Store.ListAllEntries()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/mbox")
+	w.Header().Set("Content-Disposition", "attachment; filename=entries.mbox")
+
+	mw := mbox.NewWriter(w)
+	defer mw.Close()
+
+	for _, entry := range entries {
+		if err := writeEntryMessage(mw, entry); err != nil {
+			log.Printf("Failed to write mbox message for entry %s: %v", entry.ID, err)
+		}
+	}
+}
+
+// writeEntryMessage appends a single entry, and its comment if any, as one
+// RFC 5322 message in the mbox archive.
+func writeEntryMessage(mw *mbox.Writer, entry Entry) error {
+	from := entry.Email
+	if from == "" {
+		from = "unknown@localhost"
+	}
+
+	msg, err := mw.CreateMessage(from, entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	body := entry.Message
+	if entry.Comment != "" {
+		body += fmt.Sprintf("\n\n> %s", entry.Comment)
+	}
+
+	fmt.Fprintf(msg, "From: %s <%s>\r\n", sanitizeHeaderValue(entry.Name), from)
+	fmt.Fprintf(msg, "Date: %s\r\n", entry.CreatedAt.Format("Mon, 02 Jan 2006 15:04:05 -0700"))
+	fmt.Fprintf(msg, "Subject: %s\r\n", sanitizeHeaderValue(subjectPreview(entry.Message)))
+	fmt.Fprintf(msg, "\r\n%s\r\n", body)
+
+	return nil
+}
+
+// sanitizeHeaderValue strips CR and replaces LF with a space so that
+// attacker-controlled entry fields can't inject extra header lines into the
+// exported message
+func sanitizeHeaderValue(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// subjectPreview truncates a message into a one-line subject
+func subjectPreview(message string) string {
+	if len(message) <= mboxSubjectPreviewLen {
+		return message
+	}
+	return message[:mboxSubjectPreviewLen] + "..."
+}
@@ -0,0 +1,78 @@
+package guestbook
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-mbox"
+)
+
+func TestSanitizeHeaderValueStripsCRLF(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "no control characters", input: "Jane Doe", want: "Jane Doe"},
+		{name: "CRLF header injection", input: "Jane\r\nX-Injected: evil", want: "Jane X-Injected: evil"},
+		{name: "bare LF", input: "Jane\nX-Injected: evil", want: "Jane X-Injected: evil"},
+		{name: "bare CR", input: "Jane\rX-Injected: evil", want: "JaneX-Injected: evil"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := sanitizeHeaderValue(c.input); got != c.want {
+				t.Errorf("sanitizeHeaderValue(%q) = %q, want %q", c.input, got, c.want)
+			}
+		})
+	}
+}
+
+func TestWriteEntryMessageRejectsHeaderInjection(t *testing.T) {
+	entry := Entry{
+		Name:      "Jane\r\nX-Injected: evil",
+		Email:     "jane@example.com",
+		Message:   "Hello there",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	mw := mbox.NewWriter(&buf)
+	if err := writeEntryMessage(mw, entry); err != nil {
+		t.Fatalf("writeEntryMessage: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("mw.Close: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "\r\nX-Injected") || strings.Contains(out, "\nX-Injected") {
+		t.Errorf("entry.Name injected an extra header line into the message:\n%s", out)
+	}
+	if !strings.Contains(out, "Jane X-Injected: evil") {
+		t.Errorf("expected the sanitized name to still appear on the From line, got:\n%s", out)
+	}
+}
+
+func TestExportEntriesMboxRequiresAdmin(t *testing.T) {
+	store := &entryActionFakeStore{}
+	server := NewServer(store, noopMailer{}, nil, Config{AdminToken: "admin-secret"})
+	router := server.Routes()
+
+	r := httptest.NewRequest("GET", "/api/entries.mbox", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("missing admin token: status = %d, want 401", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "/api/entries.mbox", nil)
+	r.Header.Set("X-GGB-Admin-Token", "wrong")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("wrong admin token: status = %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,476 @@
+// Package guestbook implements the GoGuestBook HTTP API: a small,
+// moderated guestbook with email notifications, CAPTCHA-gated submission
+// and webhook/mbox integrations. It is designed to be embedded in larger
+// Go applications; cmd/goguestbookd wires it up as a standalone binary.
+package guestbook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	nameMinLen      = 3
+	nameMaxLen      = 100
+	emailMinLen     = 6
+	emailMaxLen     = 100
+	messageMinLen   = 10
+	messageMaxLen   = 2000
+	defaultPageSize = 20
+	maxPageSize     = 100
+	defaultLang     = "de"
+)
+
+// Entry represents a single entry in the guestbook
+type Entry struct {
+	ID            string    `json:"id" db:"id"`
+	Name          string    `json:"name" db:"name"`
+	Email         string    `json:"email" db:"email"`
+	Message       string    `json:"message" db:"message"`
+	Approved      int8      `json:"approved" db:"approved"`
+	Comment       string    `json:"comment" db:"comment"`
+	IP            string    `json:"-" db:"ip"`
+	Lang          string    `json:"lang,omitempty" db:"lang"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	CaptchaID     string    `json:"captcha_id,omitempty" db:"-"`
+	CaptchaAnswer string    `json:"captcha_answer,omitempty" db:"-"`
+}
+
+// Config holds the settings a Server needs, independent of how the caller
+// obtains them (environment variables, flags, a config file, ...).
+type Config struct {
+	SMTPHost          string
+	SMTPPort          string
+	SMTPUser          string
+	SMTPPass          string
+	AdminEmail        string
+	AdminLang         string
+	URL               string
+	EntryWaitDuration time.Duration
+	CaptchaProvider   string
+	CaptchaSecret     string
+	InboundSMTPListen string
+	InboundSMTPDomain string
+	InboundSMTPSecret string
+	AdminToken        string
+}
+
+// Server holds the dependencies of a running guestbook and exposes the
+// HTTP API via Routes(). All fields are safe to substitute with test
+// doubles (e.g. a Store backed by sqlmock, or a Mailer spy).
+type Server struct {
+	Store           Store
+	Mailer          Mailer
+	Config          Config
+	CaptchaProvider CaptchaProvider
+
+	webhookQueue chan webhookDelivery
+}
+
+// NewServer wires up a Server from its dependencies and starts its
+// background webhook worker pool.
+func NewServer(store Store, mailer Mailer, captchaProvider CaptchaProvider, cfg Config) *Server {
+	s := &Server{
+		Store:           store,
+		Mailer:          mailer,
+		Config:          cfg,
+		CaptchaProvider: captchaProvider,
+		webhookQueue:    make(chan webhookDelivery, webhookQueueSize),
+	}
+	s.startWebhookWorkers(webhookWorkerCount)
+	return s
+}
+
+// Routes builds the http.Handler serving the guestbook API
+func (s *Server) Routes() http.Handler {
+	router := mux.NewRouter()
+
+	router.HandleFunc("/api/entries", s.getApprovedEntries).Methods("GET")
+	router.HandleFunc("/api/entries", s.createEntry).Methods("POST")
+	router.HandleFunc("/api/entries/{id}", s.getEntry).Methods("GET")
+	router.HandleFunc("/api/entries/{id}/approve", s.approveEntry).Methods("POST", "GET")
+	router.HandleFunc("/api/entries/{id}/reject", s.rejectEntry).Methods("POST", "GET")
+	router.HandleFunc("/api/entries/{id}/comment", s.addComment).Methods("PUT")
+	router.HandleFunc("/api/captcha", s.getCaptcha).Methods("GET")
+	router.HandleFunc("/api/webhooks", s.requireAdmin(s.listWebhooks)).Methods("GET")
+	router.HandleFunc("/api/webhooks", s.requireAdmin(s.createWebhook)).Methods("POST")
+	router.HandleFunc("/api/webhooks/{id}", s.requireAdmin(s.deleteWebhook)).Methods("DELETE")
+	router.HandleFunc("/api/entries.mbox", s.requireAdmin(s.exportEntriesMbox)).Methods("GET")
+	router.HandleFunc("/static/demo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		http.ServeFile(w, r, "demo.html")
+	})
+	router.HandleFunc("/static/GoGuestBook.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript")
+		http.ServeFile(w, r, "GoGuestBook.js")
+	})
+
+	return router
+}
+
+// requireAdmin wraps an admin-only handler, rejecting requests that don't
+// present the configured AdminToken via the X-GGB-Admin-Token header.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-GGB-Admin-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Config.AdminToken)) != 1 {
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// entryList is the paginated response returned by getApprovedEntries
+type entryList struct {
+	Total   int     `json:"total"`
+	Page    int     `json:"page"`
+	Entries []Entry `json:"entries"`
+}
+
+// getApprovedEntries returns a page of approved guestbook entries
+func (s *Server) getApprovedEntries(w http.ResponseWriter, r *http.Request) {
+	page, pageSize, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := EntryFilter{Page: page, PageSize: pageSize, Order: "DESC"}
+	if r.URL.Query().Get("order") == "asc" {
+		filter.Order = "ASC"
+	}
+	if since := r.URL.Query().Get("since"); since != "" {
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+		filter.Since = &sinceTime
+	}
+	if until := r.URL.Query().Get("until"); until != "" {
+		untilTime, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			http.Error(w, "invalid until", http.StatusBadRequest)
+			return
+		}
+		filter.Until = &untilTime
+	}
+
+	entries, total, err := s.Store.ListApprovedEntries(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entryList{Total: total, Page: page, Entries: entries})
+}
+
+// parsePagination reads and validates the p/psz query parameters
+func parsePagination(r *http.Request) (page int, pageSize int, err error) {
+	page = 1
+	if p := r.URL.Query().Get("p"); p != "" {
+		page, err = strconv.Atoi(p)
+		if err != nil || page < 1 {
+			return 0, 0, errors.New("invalid p")
+		}
+	}
+
+	pageSize = defaultPageSize
+	if psz := r.URL.Query().Get("psz"); psz != "" {
+		pageSize, err = strconv.Atoi(psz)
+		if err != nil || pageSize < 1 {
+			return 0, 0, errors.New("invalid psz")
+		}
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	return page, pageSize, nil
+}
+
+// createEntry creates a new guestbook entry
+func (s *Server) createEntry(w http.ResponseWriter, r *http.Request) {
+	var entry Entry
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Spam protection via the configured CAPTCHA provider
+	ok, err := s.CaptchaProvider.Verify(entry.CaptchaID, entry.CaptchaAnswer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"error": "captcha"})
+		return
+	}
+
+	// Input validation
+	if err := validateEntry(&entry); err != nil {
+		http.Error(w, "", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"error": "validation"})
+		return
+	}
+
+	// Enforce wait time from same IP between new posts
+	ip := r.Header.Get("X-Forwarded-For")
+	if ip == "" {
+		ip, _, err = net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	prevEntry, err := s.Store.MostRecentEntryFromIP(ip)
+	if err == nil {
+		if time.Since(prevEntry.CreatedAt) < s.Config.EntryWaitDuration {
+			http.Error(w, "", http.StatusTooManyRequests)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"error": "postlimit"})
+			return
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Save the entry
+	id, err := generateID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	entry.ID = id
+	entry.IP = ip
+	if entry.Lang == "" {
+		entry.Lang = defaultLang
+	}
+	if err := s.Store.InsertEntry(entry); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.enqueueWebhook(webhookEventEntryCreated, entry)
+
+	// Send email notification to admin
+	url := fmt.Sprintf("%s?GgbEntryID=%s", s.Config.URL, id)
+	err = s.Mailer.Send(s.Config.AdminEmail, s.replyToAddress(id), s.Config.AdminLang, tmplAdminEntryAdded, mailData{
+		Entry:       entry,
+		URL:         url,
+		ApproveLink: fmt.Sprintf("%s/api/entries/%s/approve?token=%s", s.Config.URL, id, s.entryActionToken("approve", id)),
+		RejectLink:  fmt.Sprintf("%s/api/entries/%s/reject?token=%s", s.Config.URL, id, s.entryActionToken("reject", id)),
+	})
+	if err != nil {
+		log.Printf("Failed to send email to admin: %v", err)
+	}
+	w.WriteHeader(http.StatusCreated)
+	// Do not leak the random ID to the submitter here!
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// validateEntry checks the validity of the guestbook entry fields
+func validateEntry(entry *Entry) error {
+	if len(entry.Name) < nameMinLen || len(entry.Name) > nameMaxLen {
+		return fmt.Errorf("name must be between %d and %d characters", nameMinLen, nameMaxLen)
+	}
+	if len(entry.Email) < emailMinLen || len(entry.Email) > emailMaxLen {
+		return fmt.Errorf("email must be between %d and %d characters", emailMinLen, emailMaxLen)
+	}
+	if len(entry.Message) < messageMinLen || len(entry.Message) > messageMaxLen {
+		return fmt.Errorf("message must be between %d and %d characters", messageMinLen, messageMaxLen)
+	}
+	if entry.Lang != "" && !ValidLocale(entry.Lang) {
+		return fmt.Errorf("unsupported lang %q", entry.Lang)
+	}
+
+	// Simple email format validation (basic regex)
+	if !isValidEmail(entry.Email) {
+		return errors.New("invalid email format")
+	}
+
+	return nil
+}
+
+// isValidEmail checks if the email format is valid
+func isValidEmail(email string) bool {
+	// A simple regex for validating an email address
+	const emailRegex = `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
+	re := regexp.MustCompile(emailRegex)
+	return re.MatchString(email)
+}
+
+// generateID generates a cryptographically secure random string as ID
+func generateID() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// getEntry returns a single guestbook entry by ID
+func (s *Server) getEntry(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+	entry, err := s.Store.GetEntry(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Entry not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// entryActionToken computes an HMAC authorizing a GET request to
+// approve/reject entryID. Admin notification mails embed it in
+// ApproveLink/RejectLink so that a plain click from a mail client - which
+// can only issue a GET and cannot set the X-GGB-Admin-Token header used
+// elsewhere - is still authenticated.
+func (s *Server) entryActionToken(action, entryID string) string {
+	mac := hmac.New(sha256.New, []byte(s.Config.AdminToken))
+	mac.Write([]byte(action + ":" + entryID))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// checkEntryActionToken verifies the "token" query parameter of a GET
+// request against entryActionToken.
+func (s *Server) checkEntryActionToken(r *http.Request, action, entryID string) bool {
+	token := r.URL.Query().Get("token")
+	return token != "" && hmac.Equal([]byte(token), []byte(s.entryActionToken(action, entryID)))
+}
+
+// approveEntry approves a guestbook entry by ID
+func (s *Server) approveEntry(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	if r.Method == http.MethodGet && !s.checkEntryActionToken(r, "approve", id) {
+		http.Error(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := s.Store.ApproveEntry(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Entry not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Send email to the author
+	if result.Entry.Email != "" && !result.WasAlreadyApproved {
+		// Don't attempt to send to empty mail addresses.
+		// Don't send mail multiple times for the same post.
+		err = s.Mailer.Send(result.Entry.Email, "", result.Entry.Lang, tmplEntryApproved, mailData{Entry: result.Entry, URL: s.Config.URL})
+		if err != nil {
+			log.Printf("Failed to send email to author: %v", err)
+		}
+	}
+
+	s.enqueueWebhook(webhookEventEntryApproved, result.Entry)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// rejectEntry rejects a guestbook entry by ID
+func (s *Server) rejectEntry(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	if r.Method == http.MethodGet && !s.checkEntryActionToken(r, "reject", id) {
+		http.Error(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := s.Store.RejectEntry(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Entry not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.enqueueWebhook(webhookEventEntryRejected, entry)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addComment adds a comment to a guestbook entry by ID
+func (s *Server) addComment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	var comment struct {
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.setComment(id, comment.Comment); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Entry not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setComment stores the comment for an entry and, the first time around,
+// notifies the author by email. It is the shared code path used by both
+// the HTTP API and the inbound SMTP reply handler.
+func (s *Server) setComment(id string, comment string) error {
+	result, err := s.Store.SetComment(id, comment)
+	if err != nil {
+		return err
+	}
+
+	// Send email to the author
+	if result.Entry.Email != "" && !result.HadPriorComment {
+		// Don't send mail if no email address is stored.
+		// Don't send mail multiple times for the same post.
+		err = s.Mailer.Send(result.Entry.Email, "", result.Entry.Lang, tmplEntryComment, mailData{Entry: result.Entry, URL: s.Config.URL})
+		if err != nil {
+			log.Printf("Failed to send email to author: %v", err)
+		}
+	}
+
+	s.enqueueWebhook(webhookEventEntryCommented, result.Entry)
+	return nil
+}
@@ -0,0 +1,165 @@
+package guestbook
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// entryActionFakeStore is a minimal Store double covering the ApproveEntry
+// and RejectEntry calls exercised by approveEntry/rejectEntry.
+type entryActionFakeStore struct {
+	entry Entry
+}
+
+func (f *entryActionFakeStore) ApproveEntry(id string) (ApproveResult, error) {
+	return ApproveResult{Entry: f.entry}, nil
+}
+func (f *entryActionFakeStore) RejectEntry(id string) (Entry, error) { return f.entry, nil }
+
+func (f *entryActionFakeStore) Init() error             { panic("not used by this test") }
+func (f *entryActionFakeStore) InsertEntry(Entry) error { panic("not used by this test") }
+func (f *entryActionFakeStore) GetEntry(string) (Entry, error) {
+	panic("not used by this test")
+}
+func (f *entryActionFakeStore) ListApprovedEntries(EntryFilter) ([]Entry, int, error) {
+	panic("not used by this test")
+}
+func (f *entryActionFakeStore) ListAllEntries() ([]Entry, error) { panic("not used by this test") }
+func (f *entryActionFakeStore) SetComment(string, string) (SetCommentResult, error) {
+	panic("not used by this test")
+}
+func (f *entryActionFakeStore) MostRecentEntryFromIP(string) (Entry, error) {
+	panic("not used by this test")
+}
+func (f *entryActionFakeStore) ListWebhooks() ([]Webhook, error) { return nil, nil }
+func (f *entryActionFakeStore) InsertWebhook(Webhook) error      { panic("not used by this test") }
+func (f *entryActionFakeStore) DeleteWebhook(string) (bool, error) {
+	panic("not used by this test")
+}
+func (f *entryActionFakeStore) CreateCaptchaChallenge(CaptchaChallenge) error {
+	panic("not used by this test")
+}
+func (f *entryActionFakeStore) ConsumeCaptchaChallenge(string) (CaptchaChallenge, bool, error) {
+	panic("not used by this test")
+}
+
+var _ Store = (*entryActionFakeStore)(nil)
+
+func TestApproveEntryGETRequiresValidToken(t *testing.T) {
+	store := &entryActionFakeStore{entry: Entry{ID: "entry1"}}
+	server := NewServer(store, noopMailer{}, nil, Config{AdminToken: "admin-secret"})
+
+	router := server.Routes()
+
+	r := httptest.NewRequest("GET", "/api/entries/entry1/approve", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("missing token: status = %d, want 401", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "/api/entries/entry1/approve?token=wrong", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("wrong token: status = %d, want 401", w.Code)
+	}
+
+	token := server.entryActionToken("approve", "entry1")
+	r = httptest.NewRequest("GET", "/api/entries/entry1/approve?token="+token, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 204 {
+		t.Errorf("valid token: status = %d, want 204", w.Code)
+	}
+}
+
+func TestRejectEntryGETRequiresValidToken(t *testing.T) {
+	store := &entryActionFakeStore{entry: Entry{ID: "entry1"}}
+	server := NewServer(store, noopMailer{}, nil, Config{AdminToken: "admin-secret"})
+
+	router := server.Routes()
+
+	r := httptest.NewRequest("GET", "/api/entries/entry1/reject", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("missing token: status = %d, want 401", w.Code)
+	}
+
+	token := server.entryActionToken("reject", "entry1")
+	r = httptest.NewRequest("GET", "/api/entries/entry1/reject?token="+token, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 204 {
+		t.Errorf("valid token: status = %d, want 204", w.Code)
+	}
+}
+
+func TestRequireAdminRejectsMissingOrWrongToken(t *testing.T) {
+	store := &entryActionFakeStore{}
+	server := NewServer(store, noopMailer{}, nil, Config{AdminToken: "admin-secret"})
+	router := server.Routes()
+
+	r := httptest.NewRequest("GET", "/api/webhooks", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("missing admin token: status = %d, want 401", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "/api/webhooks", nil)
+	r.Header.Set("X-GGB-Admin-Token", "wrong")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("wrong admin token: status = %d, want 401", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "/api/webhooks", nil)
+	r.Header.Set("X-GGB-Admin-Token", "admin-secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Errorf("correct admin token: status = %d, want 200", w.Code)
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	cases := []struct {
+		name         string
+		query        string
+		wantPage     int
+		wantPageSize int
+		wantErr      bool
+	}{
+		{name: "defaults", query: "", wantPage: 1, wantPageSize: defaultPageSize},
+		{name: "explicit page and size", query: "p=3&psz=10", wantPage: 3, wantPageSize: 10},
+		{name: "size is capped at maxPageSize", query: "psz=1000", wantPage: 1, wantPageSize: maxPageSize},
+		{name: "invalid page", query: "p=0", wantErr: true},
+		{name: "non-numeric page", query: "p=abc", wantErr: true},
+		{name: "invalid page size", query: "psz=-1", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/entries?"+c.query, nil)
+			page, pageSize, err := parsePagination(r)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if page != c.wantPage {
+				t.Errorf("page = %d, want %d", page, c.wantPage)
+			}
+			if pageSize != c.wantPageSize {
+				t.Errorf("pageSize = %d, want %d", pageSize, c.wantPageSize)
+			}
+		})
+	}
+}
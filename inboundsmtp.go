@@ -0,0 +1,174 @@
+package guestbook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"github.com/emersion/go-smtp"
+)
+
+// replyToAddress builds the Reply-To address embedded in admin notification
+// emails. Its local-part carries an HMAC over the entry ID so that inbound
+// mail can later be verified without having to keep server-side state.
+func (s *Server) replyToAddress(entryID string) string {
+	if s.Config.InboundSMTPDomain == "" {
+		return ""
+	}
+	return fmt.Sprintf("comment+%s-%s@%s", entryID, s.commentToken(entryID), s.Config.InboundSMTPDomain)
+}
+
+// commentToken computes the HMAC that authenticates a comment local-part.
+// It is keyed by InboundSMTPSecret rather than SMTPPass: SMTPPass is
+// routinely empty for no-auth relays, which would make the token
+// forgeable by anyone who learns an entry ID.
+func (s *Server) commentToken(entryID string) string {
+	mac := hmac.New(sha256.New, []byte(s.Config.InboundSMTPSecret))
+	mac.Write([]byte(entryID))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// parseCommentAddress extracts and verifies the entry ID from a
+// "comment+<entryID>-<hmac>@domain" address
+func (s *Server) parseCommentAddress(address string) (string, error) {
+	local := address
+	if at := strings.LastIndex(address, "@"); at != -1 {
+		local = address[:at]
+	}
+	if !strings.HasPrefix(local, "comment+") {
+		return "", fmt.Errorf("not a comment address")
+	}
+	local = strings.TrimPrefix(local, "comment+")
+	sep := strings.LastIndex(local, "-")
+	if sep == -1 {
+		return "", fmt.Errorf("malformed comment address")
+	}
+	entryID, token := local[:sep], local[sep+1:]
+	if !hmac.Equal([]byte(token), []byte(s.commentToken(entryID))) {
+		return "", fmt.Errorf("invalid comment token")
+	}
+	return entryID, nil
+}
+
+// RunInboundSMTPServer starts the embedded SMTP server that accepts replies
+// to the admin notification mails and turns them into comments. It blocks
+// until the server stops, so callers typically run it in its own goroutine.
+func (s *Server) RunInboundSMTPServer() error {
+	srv := smtp.NewServer(&inboundBackend{server: s})
+	srv.Addr = s.Config.InboundSMTPListen
+	srv.Domain = s.Config.InboundSMTPDomain
+	srv.AllowInsecureAuth = true
+	log.Printf("Inbound SMTP server listening on %s", srv.Addr)
+	return srv.ListenAndServe()
+}
+
+// inboundBackend implements smtp.Backend
+type inboundBackend struct {
+	server *Server
+}
+
+func (b *inboundBackend) NewSession(c *smtp.Conn) (smtp.Session, error) {
+	return &inboundSession{server: b.server}, nil
+}
+
+// inboundSession implements smtp.Session
+type inboundSession struct {
+	server  *Server
+	entryID string
+}
+
+func (sess *inboundSession) Mail(from string, opts *smtp.MailOptions) error {
+	return nil
+}
+
+func (sess *inboundSession) Rcpt(to string, opts *smtp.RcptOptions) error {
+	entryID, err := sess.server.parseCommentAddress(to)
+	if err != nil {
+		return fmt.Errorf("unknown recipient: %w", err)
+	}
+	sess.entryID = entryID
+	return nil
+}
+
+func (sess *inboundSession) Data(r io.Reader) error {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return err
+	}
+	body, err := extractPlainTextBody(msg.Header, msg.Body)
+	if err != nil {
+		return err
+	}
+	return sess.server.setComment(sess.entryID, body)
+}
+
+// extractPlainTextBody pulls the text/plain reply out of an inbound
+// message. Real mail clients commonly reply with a multipart/alternative
+// body carrying both a text/plain and a text/html part, so this walks
+// multipart messages looking for the former rather than treating the raw
+// body (MIME boundaries included) as the comment. Both the top-level
+// message and individual parts may carry their own
+// Content-Transfer-Encoding, which is decoded before returning.
+func extractPlainTextBody(header mail.Header, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// No (valid) Content-Type: treat the body as plain text as-is.
+		return decodeBody(body, header.Get("Content-Transfer-Encoding"))
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return decodeBody(body, header.Get("Content-Transfer-Encoding"))
+	}
+
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil || partType == "" {
+			partType = "text/plain"
+		}
+		if partType == "text/plain" {
+			return decodeBody(part, part.Header.Get("Content-Transfer-Encoding"))
+		}
+	}
+	return "", fmt.Errorf("no text/plain part found")
+}
+
+// decodeBody reads r fully, decoding it according to a
+// Content-Transfer-Encoding header value (quoted-printable or base64;
+// anything else, including an empty value, is read verbatim)
+func decodeBody(r io.Reader, transferEncoding string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(transferEncoding)) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func (sess *inboundSession) Reset() {}
+
+func (sess *inboundSession) Logout() error {
+	return nil
+}
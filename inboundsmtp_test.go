@@ -0,0 +1,206 @@
+package guestbook
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestParseCommentAddress(t *testing.T) {
+	s := &Server{Config: Config{InboundSMTPSecret: "s3cr3t"}}
+	entryID := "abc123"
+	address := "comment+" + entryID + "-" + s.commentToken(entryID) + "@example.com"
+
+	gotID, err := s.parseCommentAddress(address)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotID != entryID {
+		t.Errorf("entryID = %q, want %q", gotID, entryID)
+	}
+}
+
+func TestParseCommentAddressRejectsTamperedToken(t *testing.T) {
+	s := &Server{Config: Config{InboundSMTPSecret: "s3cr3t"}}
+	entryID := "abc123"
+	address := "comment+" + entryID + "-deadbeefdeadbeef@example.com"
+
+	if _, err := s.parseCommentAddress(address); err == nil {
+		t.Fatal("expected an error for a forged token, got none")
+	}
+}
+
+func TestParseCommentAddressRejectsDifferentSecret(t *testing.T) {
+	issuer := &Server{Config: Config{InboundSMTPSecret: "issuer-secret"}}
+	verifier := &Server{Config: Config{InboundSMTPSecret: "other-secret"}}
+	entryID := "abc123"
+	address := "comment+" + entryID + "-" + issuer.commentToken(entryID) + "@example.com"
+
+	if _, err := verifier.parseCommentAddress(address); err == nil {
+		t.Fatal("expected an error when InboundSMTPSecret differs, got none")
+	}
+}
+
+func TestParseCommentAddressRejectsMalformed(t *testing.T) {
+	s := &Server{Config: Config{InboundSMTPSecret: "s3cr3t"}}
+
+	cases := []string{
+		"not-a-comment-address@example.com",
+		"comment+missingseparator@example.com",
+	}
+	for _, address := range cases {
+		if _, err := s.parseCommentAddress(address); err == nil {
+			t.Errorf("parseCommentAddress(%q): expected an error, got none", address)
+		}
+	}
+}
+
+func TestExtractPlainTextBodyPlain(t *testing.T) {
+	raw := "From: jane@example.com\r\n" +
+		"To: comment+x@example.com\r\n" +
+		"Subject: Re: entry\r\n" +
+		"\r\n" +
+		"Looks good, approved!\r\n"
+
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("mail.ReadMessage: %v", err)
+	}
+	body, err := extractPlainTextBody(msg.Header, msg.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body != "Looks good, approved!\r\n" {
+		t.Errorf("body = %q, want %q", body, "Looks good, approved!\r\n")
+	}
+}
+
+func TestExtractPlainTextBodyMultipartAlternative(t *testing.T) {
+	raw := "From: jane@example.com\r\n" +
+		"To: comment+x@example.com\r\n" +
+		"Subject: Re: entry\r\n" +
+		"Content-Type: multipart/alternative; boundary=BOUNDARY\r\n" +
+		"\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Looks good, approved!\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<p>Looks good, approved!</p>\r\n" +
+		"--BOUNDARY--\r\n"
+
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("mail.ReadMessage: %v", err)
+	}
+	body, err := extractPlainTextBody(msg.Header, msg.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(body, "BOUNDARY") {
+		t.Errorf("body leaked the MIME boundary marker: %q", body)
+	}
+	if strings.Contains(body, "<p>") {
+		t.Errorf("body contained the html part instead of the text part: %q", body)
+	}
+	if !strings.Contains(body, "Looks good, approved!") {
+		t.Errorf("body = %q, want it to contain the text/plain reply", body)
+	}
+}
+
+func TestExtractPlainTextBodyQuotedPrintable(t *testing.T) {
+	raw := "From: jane@example.com\r\n" +
+		"To: comment+x@example.com\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"Stra=C3=9Fe looks approved=21\r\n"
+
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("mail.ReadMessage: %v", err)
+	}
+	body, err := extractPlainTextBody(msg.Header, msg.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Stra\xc3\x9fe looks approved!\r\n"; body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+// commentFakeStore is a minimal Store double covering just the SetComment
+// and ListWebhooks calls exercised by inboundSession.Data -> setComment.
+type commentFakeStore struct {
+	entry Entry
+}
+
+func (f *commentFakeStore) SetComment(id string, comment string) (SetCommentResult, error) {
+	f.entry.Comment = comment
+	return SetCommentResult{Entry: f.entry}, nil
+}
+func (f *commentFakeStore) ListWebhooks() ([]Webhook, error) { return nil, nil }
+
+func (f *commentFakeStore) Init() error                    { panic("not used by this test") }
+func (f *commentFakeStore) InsertEntry(Entry) error        { panic("not used by this test") }
+func (f *commentFakeStore) GetEntry(string) (Entry, error) { panic("not used by this test") }
+func (f *commentFakeStore) ListApprovedEntries(EntryFilter) ([]Entry, int, error) {
+	panic("not used by this test")
+}
+func (f *commentFakeStore) ListAllEntries() ([]Entry, error) { panic("not used by this test") }
+func (f *commentFakeStore) ApproveEntry(string) (ApproveResult, error) {
+	panic("not used by this test")
+}
+func (f *commentFakeStore) RejectEntry(string) (Entry, error) { panic("not used by this test") }
+func (f *commentFakeStore) MostRecentEntryFromIP(string) (Entry, error) {
+	panic("not used by this test")
+}
+func (f *commentFakeStore) InsertWebhook(Webhook) error { panic("not used by this test") }
+func (f *commentFakeStore) DeleteWebhook(string) (bool, error) {
+	panic("not used by this test")
+}
+func (f *commentFakeStore) CreateCaptchaChallenge(CaptchaChallenge) error {
+	panic("not used by this test")
+}
+func (f *commentFakeStore) ConsumeCaptchaChallenge(string) (CaptchaChallenge, bool, error) {
+	panic("not used by this test")
+}
+
+var _ Store = (*commentFakeStore)(nil)
+
+// noopMailer discards every mail it's asked to send, so tests can exercise
+// setComment's side effects without a real SMTP relay.
+type noopMailer struct{}
+
+func (noopMailer) Send(to string, replyTo string, lang string, templateName string, data mailData) error {
+	return nil
+}
+
+func TestInboundSessionDataExtractsPlainTextReply(t *testing.T) {
+	store := &commentFakeStore{entry: Entry{ID: "entry1", Email: "jane@example.com"}}
+	server := NewServer(store, noopMailer{}, nil, Config{InboundSMTPSecret: "s3cr3t"})
+
+	sess := &inboundSession{server: server, entryID: "entry1"}
+	raw := "From: jane@example.com\r\n" +
+		"To: comment+entry1@example.com\r\n" +
+		"Content-Type: multipart/alternative; boundary=BOUNDARY\r\n" +
+		"\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Approved via reply\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<p>Approved via reply</p>\r\n" +
+		"--BOUNDARY--\r\n"
+
+	if err := sess.Data(strings.NewReader(raw)); err != nil {
+		t.Fatalf("Data: %v", err)
+	}
+	if store.entry.Comment != "Approved via reply" {
+		t.Errorf("stored comment = %q, want the plain text part only", store.entry.Comment)
+	}
+}
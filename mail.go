@@ -0,0 +1,174 @@
+package guestbook
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	texttemplate "text/template"
+)
+
+const (
+	mailTemplateDir = "templates/mail"
+
+	tmplEntryApproved   = "entry_approved"
+	tmplEntryComment    = "entry_comment"
+	tmplAdminEntryAdded = "admin_entry_added"
+)
+
+// installedLocales lists the locale subdirectories shipped under
+// templates/mail/. Entry.Lang must match one of these before it is used
+// to build a template path, otherwise a crafted value (e.g. containing
+// "../") could walk the lookup outside mailTemplateDir.
+var installedLocales = map[string]bool{
+	"de": true,
+	"en": true,
+}
+
+// ValidLocale reports whether lang is one of the installed
+// templates/mail/ locales.
+func ValidLocale(lang string) bool {
+	return installedLocales[lang]
+}
+
+// mailData is passed to the mail templates in templates/mail/<locale>/
+type mailData struct {
+	Entry       Entry
+	URL         string
+	ApproveLink string
+	RejectLink  string
+}
+
+// Mailer sends the notifications the guestbook triggers (new entry,
+// approval, comment). Implementations can substitute an SES/Postmark
+// backend, or a spy for tests.
+type Mailer interface {
+	Send(to string, replyTo string, lang string, templateName string, data mailData) error
+}
+
+// SMTPMailer is the default Mailer: it renders templates/mail/<locale>/
+// and relays the result over SMTP.
+type SMTPMailer struct {
+	Host string
+	Port string
+	User string
+	Pass string
+	From string
+}
+
+// NewSMTPMailer builds a Mailer from the SMTP settings in cfg
+func NewSMTPMailer(cfg Config) *SMTPMailer {
+	return &SMTPMailer{Host: cfg.SMTPHost, Port: cfg.SMTPPort, User: cfg.SMTPUser, Pass: cfg.SMTPPass, From: cfg.AdminEmail}
+}
+
+// Send renders the named template in the given locale (falling back to
+// defaultLang if the locale is unknown) and sends it as a
+// multipart/alternative message with a text and an HTML part.
+func (m *SMTPMailer) Send(to string, replyTo string, lang string, name string, data mailData) error {
+	subject, textBody, htmlBody, err := m.renderMail(lang, name, data)
+	if err != nil {
+		return err
+	}
+
+	message, err := m.buildMIMEMessage(to, replyTo, subject, textBody, htmlBody)
+	if err != nil {
+		return err
+	}
+
+	return m.deliverRawEmail(to, message)
+}
+
+// renderMail renders the subject, text and HTML parts of a mail template
+func (m *SMTPMailer) renderMail(lang string, name string, data mailData) (subject string, textBody string, htmlBody string, err error) {
+	dir := filepath.Join(mailTemplateDir, lang)
+
+	subjectTmpl, err := texttemplate.ParseFiles(filepath.Join(dir, name+".subject.tmpl"))
+	if err != nil {
+		if lang == defaultLang {
+			return "", "", "", err
+		}
+		return m.renderMail(defaultLang, name, data)
+	}
+	var subjectBuf bytes.Buffer
+	if err := subjectTmpl.Execute(&subjectBuf, data); err != nil {
+		return "", "", "", err
+	}
+
+	textTmpl, err := texttemplate.ParseFiles(filepath.Join(dir, name+".txt.tmpl"))
+	if err != nil {
+		return "", "", "", err
+	}
+	var textBuf bytes.Buffer
+	if err := textTmpl.Execute(&textBuf, data); err != nil {
+		return "", "", "", err
+	}
+
+	htmlTmpl, err := htmltemplate.ParseFiles(filepath.Join(dir, name+".html.tmpl"))
+	if err != nil {
+		return "", "", "", err
+	}
+	var htmlBuf bytes.Buffer
+	if err := htmlTmpl.Execute(&htmlBuf, data); err != nil {
+		return "", "", "", err
+	}
+
+	return subjectBuf.String(), textBuf.String(), htmlBuf.String(), nil
+}
+
+// buildMIMEMessage assembles a multipart/alternative message with
+// quoted-printable encoded UTF-8 headers and bodies.
+func (m *SMTPMailer) buildMIMEMessage(to string, replyTo string, subject string, textBody string, htmlBody string) ([]byte, error) {
+	var buf bytes.Buffer
+	qEncoding := mime.QEncoding
+
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	if replyTo != "" {
+		fmt.Fprintf(&buf, "Reply-To: %s\r\n", replyTo)
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", qEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+
+	writer := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	if err := writeQuotedPrintablePart(writer, "text/plain", textBody); err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintablePart(writer, "text/html", htmlBody); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintablePart writes a single quoted-printable encoded,
+// UTF-8 part of a multipart/alternative message.
+func writeQuotedPrintablePart(writer *multipart.Writer, contentType string, body string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=\"UTF-8\"")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// deliverRawEmail hands a fully assembled MIME message to the SMTP relay
+func (m *SMTPMailer) deliverRawEmail(to string, message []byte) error {
+	auth := smtp.PlainAuth("", m.User, m.Pass, m.Host)
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.User, []string{to}, message)
+}
@@ -0,0 +1,31 @@
+package guestbook
+
+import "testing"
+
+func TestRenderMailFallsBackToDefaultLang(t *testing.T) {
+	m := &SMTPMailer{}
+	data := mailData{Entry: Entry{Name: "Jane"}, URL: "https://example.com"}
+
+	subject, textBody, htmlBody, err := m.renderMail("xx", tmplAdminEntryAdded, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subject == "" || textBody == "" || htmlBody == "" {
+		t.Error("expected non-empty subject/text/html parts from the de fallback")
+	}
+
+	wantSubject, wantText, wantHTML, err := m.renderMail(defaultLang, tmplAdminEntryAdded, data)
+	if err != nil {
+		t.Fatalf("unexpected error rendering %s directly: %v", defaultLang, err)
+	}
+	if subject != wantSubject || textBody != wantText || htmlBody != wantHTML {
+		t.Error("expected an unknown lang to render identically to defaultLang")
+	}
+}
+
+func TestRenderMailUnknownTemplateErrors(t *testing.T) {
+	m := &SMTPMailer{}
+	if _, _, _, err := m.renderMail(defaultLang, "no_such_template", mailData{}); err == nil {
+		t.Fatal("expected an error for an unknown template name")
+	}
+}
@@ -0,0 +1,311 @@
+package guestbook
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EntryFilter narrows down ListApprovedEntries
+type EntryFilter struct {
+	Since    *time.Time
+	Until    *time.Time
+	Order    string // "ASC" or "DESC"
+	Page     int
+	PageSize int
+}
+
+// ApproveResult is returned by Store.ApproveEntry
+type ApproveResult struct {
+	Entry Entry
+	// WasAlreadyApproved is true if the entry had already been moderated
+	// before this call, i.e. approved or rejected.
+	WasAlreadyApproved bool
+}
+
+// SetCommentResult is returned by Store.SetComment
+type SetCommentResult struct {
+	Entry           Entry
+	HadPriorComment bool
+}
+
+// CaptchaChallenge is a single outstanding builtin CAPTCHA challenge
+type CaptchaChallenge struct {
+	ID        string    `db:"id"`
+	Answer    string    `db:"answer"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// Store abstracts the guestbook's persistence, so that the SQLite backend
+// used today can eventually be swapped for Postgres, MySQL, ... This
+// covers entries as well as the webhook subscriptions and builtin CAPTCHA
+// challenges, so no other part of the guestbook needs to reach for
+// SQLite-specific code directly.
+type Store interface {
+	Init() error
+	InsertEntry(entry Entry) error
+	GetEntry(id string) (Entry, error)
+	ListApprovedEntries(filter EntryFilter) ([]Entry, int, error)
+	ListAllEntries() ([]Entry, error)
+	ApproveEntry(id string) (ApproveResult, error)
+	RejectEntry(id string) (Entry, error)
+	SetComment(id string, comment string) (SetCommentResult, error)
+	MostRecentEntryFromIP(ip string) (Entry, error)
+
+	ListWebhooks() ([]Webhook, error)
+	InsertWebhook(wh Webhook) error
+	DeleteWebhook(id string) (bool, error)
+
+	CreateCaptchaChallenge(challenge CaptchaChallenge) error
+	// ConsumeCaptchaChallenge looks up and deletes a challenge atomically:
+	// a challenge is single-use regardless of the verification outcome.
+	// found is false if no challenge with that ID exists (already used or
+	// never issued). Expired challenges are not removed until consumed.
+	ConsumeCaptchaChallenge(id string) (challenge CaptchaChallenge, found bool, err error)
+}
+
+// SQLiteStore is the default Store implementation, backed by SQLite.
+type SQLiteStore struct {
+	DB *sqlx.DB
+}
+
+// NewSQLiteStore wraps an already-connected *sqlx.DB
+func NewSQLiteStore(db *sqlx.DB) *SQLiteStore {
+	return &SQLiteStore{DB: db}
+}
+
+// Init creates the entries, webhooks and captchas tables if they don't
+// exist yet
+func (s *SQLiteStore) Init() error {
+	_, err := s.DB.Exec(`
+        CREATE TABLE IF NOT EXISTS entries (
+            id TEXT PRIMARY KEY,
+            name TEXT NOT NULL,
+            email TEXT NOT NULL,
+            message TEXT NOT NULL,
+            ip TEXT NOT NULL,
+            approved INTEGER NOT NULL DEFAULT 0,
+            comment TEXT NOT NULL,
+            lang TEXT NOT NULL DEFAULT 'de',
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+    `)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.Exec(`
+        CREATE TABLE IF NOT EXISTS webhooks (
+            id TEXT PRIMARY KEY,
+            url TEXT NOT NULL,
+            secret TEXT NOT NULL,
+            events TEXT NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+    `)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.Exec(`
+        CREATE TABLE IF NOT EXISTS captchas (
+            id TEXT PRIMARY KEY,
+            answer TEXT NOT NULL,
+            expires_at DATETIME NOT NULL
+        );
+    `)
+	return err
+}
+
+func (s *SQLiteStore) InsertEntry(entry Entry) error {
+	_, err := s.DB.NamedExec(
+		"INSERT INTO entries (id, name, email, message, ip, comment, lang) VALUES (:id, :name, :email, :message, :ip, '', :lang)",
+		entry,
+	)
+	return err
+}
+
+func (s *SQLiteStore) GetEntry(id string) (Entry, error) {
+	var entry Entry
+	err := s.DB.Get(&entry, "SELECT * FROM entries WHERE id = ?", id)
+	return entry, err
+}
+
+func (s *SQLiteStore) ListApprovedEntries(filter EntryFilter) ([]Entry, int, error) {
+	where := "WHERE approved = 1"
+	args := []interface{}{}
+	if filter.Since != nil {
+		where += " AND created_at >= ?"
+		args = append(args, *filter.Since)
+	}
+	if filter.Until != nil {
+		where += " AND created_at <= ?"
+		args = append(args, *filter.Until)
+	}
+
+	order := filter.Order
+	if order == "" {
+		order = "DESC"
+	}
+
+	var total int
+	if err := s.DB.Get(&total, "SELECT COUNT(*) FROM entries "+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	var entries []Entry
+	// never leak ID or IP here!
+	query := fmt.Sprintf(
+		"SELECT name, message, approved, comment, created_at FROM entries %s ORDER BY created_at %s LIMIT ? OFFSET ?",
+		where, order,
+	)
+	err := s.DB.Select(&entries, query, append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+func (s *SQLiteStore) ListAllEntries() ([]Entry, error) {
+	var entries []Entry
+	err := s.DB.Select(&entries, "SELECT * FROM entries ORDER BY created_at ASC")
+	return entries, err
+}
+
+func (s *SQLiteStore) ApproveEntry(id string) (ApproveResult, error) {
+	var oldEntry Entry
+	err := s.DB.Get(&oldEntry, "SELECT email, approved, lang FROM entries WHERE id = ?", id)
+	if err != nil {
+		return ApproveResult{}, err
+	}
+
+	result, err := s.DB.Exec("UPDATE entries SET approved = 1 WHERE id = ?", id)
+	if err != nil {
+		return ApproveResult{}, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return ApproveResult{}, err
+	}
+	if rowsAffected == 0 {
+		return ApproveResult{}, sql.ErrNoRows
+	}
+
+	entry, err := s.GetEntry(id)
+	if err != nil {
+		return ApproveResult{}, err
+	}
+	return ApproveResult{Entry: entry, WasAlreadyApproved: oldEntry.Approved != 0}, nil
+}
+
+func (s *SQLiteStore) RejectEntry(id string) (Entry, error) {
+	result, err := s.DB.Exec("UPDATE entries SET approved = -1 WHERE id = ?", id)
+	if err != nil {
+		return Entry{}, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Entry{}, err
+	}
+	if rowsAffected == 0 {
+		return Entry{}, sql.ErrNoRows
+	}
+	return s.GetEntry(id)
+}
+
+func (s *SQLiteStore) SetComment(id string, comment string) (SetCommentResult, error) {
+	var oldEntry Entry
+	err := s.DB.Get(&oldEntry, "SELECT email, comment, lang FROM entries WHERE id = ?", id)
+	if err != nil {
+		return SetCommentResult{}, err
+	}
+
+	result, err := s.DB.Exec("UPDATE entries SET comment = ? WHERE id = ?", comment, id)
+	if err != nil {
+		return SetCommentResult{}, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return SetCommentResult{}, err
+	}
+	if rowsAffected == 0 {
+		return SetCommentResult{}, sql.ErrNoRows
+	}
+
+	entry, err := s.GetEntry(id)
+	if err != nil {
+		return SetCommentResult{}, err
+	}
+	return SetCommentResult{Entry: entry, HadPriorComment: oldEntry.Comment != ""}, nil
+}
+
+func (s *SQLiteStore) MostRecentEntryFromIP(ip string) (Entry, error) {
+	var entry Entry
+	err := s.DB.Get(&entry, "SELECT created_at FROM entries WHERE ip = ? ORDER BY created_at DESC LIMIT 1", ip)
+	return entry, err
+}
+
+func (s *SQLiteStore) ListWebhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	err := s.DB.Select(&webhooks, "SELECT * FROM webhooks")
+	return webhooks, err
+}
+
+func (s *SQLiteStore) InsertWebhook(wh Webhook) error {
+	_, err := s.DB.NamedExec("INSERT INTO webhooks (id, url, secret, events) VALUES (:id, :url, :secret, :events)", wh)
+	return err
+}
+
+func (s *SQLiteStore) DeleteWebhook(id string) (bool, error) {
+	result, err := s.DB.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (s *SQLiteStore) CreateCaptchaChallenge(challenge CaptchaChallenge) error {
+	_, err := s.DB.NamedExec(
+		"INSERT INTO captchas (id, answer, expires_at) VALUES (:id, :answer, :expires_at)",
+		challenge,
+	)
+	return err
+}
+
+func (s *SQLiteStore) ConsumeCaptchaChallenge(id string) (CaptchaChallenge, bool, error) {
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return CaptchaChallenge{}, false, err
+	}
+	defer tx.Rollback()
+
+	var challenge CaptchaChallenge
+	err = tx.Get(&challenge, "SELECT * FROM captchas WHERE id = ?", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return CaptchaChallenge{}, false, nil
+		}
+		return CaptchaChallenge{}, false, err
+	}
+
+	// A challenge is single-use: delete it regardless of the outcome. Doing
+	// so in the same transaction as the lookup above is what makes the
+	// whole operation atomic: a concurrent ConsumeCaptchaChallenge for the
+	// same id can't see the row between our SELECT and our DELETE.
+	if _, err := tx.Exec("DELETE FROM captchas WHERE id = ?", id); err != nil {
+		return CaptchaChallenge{}, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return CaptchaChallenge{}, false, err
+	}
+	return challenge, true, nil
+}
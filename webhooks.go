@@ -0,0 +1,226 @@
+package guestbook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	webhookEventEntryCreated   = "entry.created"
+	webhookEventEntryApproved  = "entry.approved"
+	webhookEventEntryRejected  = "entry.rejected"
+	webhookEventEntryCommented = "entry.commented"
+
+	webhookWorkerCount    = 4
+	webhookQueueSize      = 256
+	webhookMaxAttempts    = 5
+	webhookInitialDelay   = 1 * time.Second
+	webhookRequestTimeout = 10 * time.Second
+)
+
+// webhookClient is used for every webhook delivery. A slow or stalling
+// subscriber URL must not be able to hang a worker indefinitely: with a
+// fixed-size worker pool, a stuck worker eventually backs up the whole
+// queue and starts silently dropping deliveries for unrelated subscribers.
+var webhookClient = &http.Client{Timeout: webhookRequestTimeout}
+
+// Webhook is a subscription to guestbook lifecycle events
+type Webhook struct {
+	ID        string    `json:"id" db:"id"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"secret" db:"secret"`
+	Events    string    `json:"events" db:"events"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// webhookPayload is the JSON body POSTed to subscribers
+type webhookPayload struct {
+	Event     string    `json:"event"`
+	Entry     Entry     `json:"entry"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// webhookDelivery is a single queued delivery attempt
+type webhookDelivery struct {
+	url     string
+	secret  string
+	payload []byte
+}
+
+// startWebhookWorkers launches the goroutines draining s.webhookQueue
+func (s *Server) startWebhookWorkers(n int) {
+	for i := 0; i < n; i++ {
+		go s.webhookWorker()
+	}
+}
+
+func (s *Server) webhookWorker() {
+	for delivery := range s.webhookQueue {
+		deliverWebhook(delivery)
+	}
+}
+
+// enqueueWebhook looks up subscriptions for event and queues a delivery for
+// each of them. It never blocks the calling HTTP handler: if the queue is
+// full the delivery is dropped and logged.
+func (s *Server) enqueueWebhook(event string, entry Entry) {
+	webhooks, err := s.Store.ListWebhooks()
+	if err != nil {
+		log.Printf("Failed to load webhook subscriptions: %v", err)
+		return
+	}
+
+	payload, err := json.Marshal(webhookPayload{Event: event, Entry: entry, Timestamp: time.Now()})
+	if err != nil {
+		log.Printf("Failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	for _, wh := range webhooks {
+		if !webhookSubscribesTo(wh, event) {
+			continue
+		}
+		delivery := webhookDelivery{url: wh.URL, secret: wh.Secret, payload: payload}
+		select {
+		case s.webhookQueue <- delivery:
+		default:
+			log.Printf("Webhook queue full, dropping %s delivery to %s", event, wh.URL)
+		}
+	}
+}
+
+func webhookSubscribesTo(wh Webhook, event string) bool {
+	for _, e := range strings.Split(wh.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverWebhook POSTs the payload, retrying with exponential backoff, and
+// logs the final failure if every attempt is exhausted.
+func deliverWebhook(d webhookDelivery) {
+	delay := webhookInitialDelay
+	var lastErr error
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		if err := postWebhook(d); err != nil {
+			lastErr = err
+			time.Sleep(delay)
+			delay *= 2
+			continue
+		}
+		return
+	}
+	log.Printf("Webhook delivery to %s failed after %d attempts: %v", d.url, webhookMaxAttempts, lastErr)
+}
+
+func postWebhook(d webhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(d.payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GGB-Signature", "sha256="+webhookSignature(d.secret, d.payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+func webhookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookSummary is the list representation of a Webhook: it omits Secret
+// so that listing subscriptions never discloses the HMAC key used to sign
+// deliveries.
+type webhookSummary struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    string    `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// listWebhooks returns all configured webhook subscriptions, without
+// their secrets
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.Store.ListWebhooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	summaries := make([]webhookSummary, len(webhooks))
+	for i, wh := range webhooks {
+		summaries[i] = webhookSummary{ID: wh.ID, URL: wh.URL, Events: wh.Events, CreatedAt: wh.CreatedAt}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// createWebhook registers a new webhook subscription
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var wh Webhook
+	if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if wh.URL == "" || wh.Secret == "" || wh.Events == "" {
+		http.Error(w, "url, secret and events are required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := generateID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wh.ID = id
+
+	if err := s.Store.InsertWebhook(wh); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wh)
+}
+
+// deleteWebhook removes a webhook subscription by ID
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	found, err := s.Store.DeleteWebhook(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
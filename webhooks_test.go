@@ -0,0 +1,69 @@
+package guestbook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	payload := []byte(`{"event":"entry.created"}`)
+
+	sig := webhookSignature("secret", payload)
+	if sig == "" {
+		t.Fatal("expected a non-empty signature")
+	}
+	if sig != webhookSignature("secret", payload) {
+		t.Error("expected the signature to be deterministic for the same secret and payload")
+	}
+	if sig == webhookSignature("other-secret", payload) {
+		t.Error("expected a different secret to produce a different signature")
+	}
+	if sig == webhookSignature("secret", []byte(`{"event":"entry.approved"}`)) {
+		t.Error("expected a different payload to produce a different signature")
+	}
+}
+
+func TestWebhookSubscribesTo(t *testing.T) {
+	wh := Webhook{Events: "entry.created, entry.approved"}
+
+	if !webhookSubscribesTo(wh, "entry.created") {
+		t.Error("expected wh to subscribe to entry.created")
+	}
+	if !webhookSubscribesTo(wh, "entry.approved") {
+		t.Error("expected wh to subscribe to entry.approved")
+	}
+	if webhookSubscribesTo(wh, "entry.rejected") {
+		t.Error("expected wh not to subscribe to entry.rejected")
+	}
+}
+
+func TestPostWebhookTimesOutOnAStallingSubscriber(t *testing.T) {
+	original := webhookClient.Timeout
+	webhookClient.Timeout = 50 * time.Millisecond
+	defer func() { webhookClient.Timeout = original }()
+
+	blockUntilClientGivesUp := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blockUntilClientGivesUp
+	}))
+	defer func() {
+		close(blockUntilClientGivesUp)
+		srv.Close()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- postWebhook(webhookDelivery{url: srv.URL, secret: "secret", payload: []byte(`{}`)})
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected a timeout error from a stalling subscriber, got none")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("postWebhook did not respect webhookClient.Timeout and hung instead")
+	}
+}